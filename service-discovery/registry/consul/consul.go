@@ -0,0 +1,288 @@
+// Package consul implements registry.Registry on top of HashiCorp Consul's
+// service catalog, for deployments where a Consul agent is already running
+// alongside mocktimism (e.g. in Kubernetes or containerized CI).
+package consul
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/samtvlabs/mocktimism/service-discovery/registry"
+)
+
+// Registry discovers and announces services via a Consul agent.
+type Registry struct {
+	client *capi.Client
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New connects to the Consul agent described by cfg. A nil cfg uses the
+// client library's defaults (CONSUL_HTTP_ADDR or http://127.0.0.1:8500).
+func New(cfg *capi.Config) (*Registry, error) {
+	if cfg == nil {
+		cfg = capi.DefaultConfig()
+	}
+
+	client, err := capi.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("consul: failed to create client: %w: %v", registry.ErrResolverUnavailable, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Registry{client: client, cancels: make(map[string]context.CancelFunc), ctx: ctx, cancel: cancel}, nil
+}
+
+// Register announces s as a Consul service registration and spawns s.Start
+// under a goroutine tied to the Registry's lifetime, so Deregister can stop
+// it. Returns ErrDuplicateService if a service with the same ID already has
+// a Start(ctx) goroutine running.
+func (r *Registry) Register(s registry.Service) error {
+	var tags []string
+	if configMap, ok := s.Config().(map[string]string); ok {
+		for key, val := range configMap {
+			tags = append(tags, key+"="+val)
+		}
+	}
+
+	// Reserve the ID under the lock before the (network-bound)
+	// ServiceRegister call, so two concurrent Register calls for the same
+	// ID can't both pass the duplicate check and race to clobber each
+	// other's cancel func, leaking the loser's Start(ctx) goroutine.
+	r.mu.Lock()
+	if _, exists := r.cancels[s.ID()]; exists {
+		r.mu.Unlock()
+		return fmt.Errorf("consul: %w: %q", registry.ErrDuplicateService, s.ID())
+	}
+	r.cancels[s.ID()] = nil
+	r.mu.Unlock()
+
+	reg := &capi.AgentServiceRegistration{
+		ID:      s.ID(),
+		Name:    s.ServiceType(),
+		Address: s.Hostname(),
+		Port:    s.Port(),
+		Tags:    tags,
+	}
+
+	if err := r.client.Agent().ServiceRegister(reg); err != nil {
+		r.mu.Lock()
+		delete(r.cancels, s.ID())
+		r.mu.Unlock()
+		return fmt.Errorf("consul: %w: service %q: %v", registry.ErrRegistrationFailed, s.ID(), err)
+	}
+
+	svcCtx, cancel := context.WithCancel(r.ctx)
+	r.mu.Lock()
+	r.cancels[s.ID()] = cancel
+	r.mu.Unlock()
+
+	// Start errors aren't surfaced: unlike mdns.Registry, consul has no
+	// Logger option to report them through.
+	go func() {
+		_ = s.Start(svcCtx)
+	}()
+
+	return nil
+}
+
+// Deregister removes the service with the given ID from the Consul catalog,
+// cancelling its Start(ctx) goroutine if one was spawned for it. Returns
+// ErrServiceNotFound if id isn't registered with the local agent.
+func (r *Registry) Deregister(id string) error {
+	services, err := r.client.Agent().Services()
+	if err != nil {
+		return fmt.Errorf("consul: failed to look up service %q: %w", id, err)
+	}
+	if _, ok := services[id]; !ok {
+		return fmt.Errorf("consul: %w: %q", registry.ErrServiceNotFound, id)
+	}
+
+	if err := r.client.Agent().ServiceDeregister(id); err != nil {
+		return fmt.Errorf("consul: failed to deregister service %q: %w", id, err)
+	}
+
+	r.mu.Lock()
+	cancel, ok := r.cancels[id]
+	delete(r.cancels, id)
+	r.mu.Unlock()
+	if ok && cancel != nil {
+		cancel()
+	}
+	return nil
+}
+
+// GetService returns the healthy catalog entries for the given service
+// name. Returns ErrServiceNotFound if none are found.
+func (r *Registry) GetService(name string) ([]*registry.ServiceEntry, error) {
+	services, _, err := r.client.Health().Service(name, "", true, nil)
+	if err != nil {
+		return nil, fmt.Errorf("consul: failed to query service %q: %w", name, err)
+	}
+	if len(services) == 0 {
+		return nil, fmt.Errorf("consul: %w: %q", registry.ErrServiceNotFound, name)
+	}
+
+	entries := make([]*registry.ServiceEntry, 0, len(services))
+	for _, svc := range services {
+		entries = append(entries, toServiceEntry(svc.Service))
+	}
+	return entries, nil
+}
+
+// ListServices returns every service currently registered in the Consul
+// catalog.
+func (r *Registry) ListServices() ([]*registry.ServiceEntry, error) {
+	services, _, err := r.client.Catalog().Services(nil)
+	if err != nil {
+		return nil, fmt.Errorf("consul: failed to list services: %w", err)
+	}
+
+	var entries []*registry.ServiceEntry
+	for name := range services {
+		matches, err := r.GetService(name)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, matches...)
+	}
+	return entries, nil
+}
+
+// Watch polls the Consul catalog for changes using blocking queries and,
+// after each poll, reports every healthy entry as an EventAdded (first
+// seen) or EventUpdated (seen on an earlier poll) until ctx is cancelled or
+// a catalog query fails. Each blocking
+// query is itself bound to ctx so cancellation interrupts an in-flight wait
+// immediately, rather than only being noticed between queries. Services
+// that drop out of the catalog between polls are reported as EventRemoved.
+func (r *Registry) Watch(ctx context.Context) (<-chan registry.Event, error) {
+	events := make(chan registry.Event)
+
+	go func() {
+		defer close(events)
+
+		var lastIndex uint64
+		seen := make(map[string]*registry.ServiceEntry)
+
+		for {
+			if ctx.Err() != nil {
+				return
+			}
+
+			services, meta, err := r.client.Catalog().Services((&capi.QueryOptions{
+				WaitIndex: lastIndex,
+			}).WithContext(ctx))
+			if err != nil {
+				return
+			}
+			lastIndex = meta.LastIndex
+
+			var current []*registry.ServiceEntry
+			for name := range services {
+				matches, err := r.GetService(name)
+				if err != nil {
+					continue
+				}
+				current = append(current, matches...)
+			}
+
+			for _, evt := range diffCatalog(seen, current) {
+				select {
+				case events <- evt:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// diffCatalog compares current, the set of entries seen on this poll,
+// against seen, the set reported on the previous poll, and returns the
+// Added/Updated events for current plus a Removed event for anything in
+// seen that dropped out of current. It mutates seen in place to reflect the
+// new state, ready for the next poll. Pulled out of Watch's poll loop so
+// the seen/current diffing — in particular EventRemoved — can be tested
+// without a live Consul agent.
+func diffCatalog(seen map[string]*registry.ServiceEntry, current []*registry.ServiceEntry) []registry.Event {
+	var events []registry.Event
+
+	currentIDs := make(map[string]bool, len(current))
+	for _, entry := range current {
+		currentIDs[entry.ID] = true
+		evtType := registry.EventAdded
+		if _, ok := seen[entry.ID]; ok {
+			evtType = registry.EventUpdated
+		}
+		events = append(events, registry.Event{Type: evtType, Service: entry})
+		seen[entry.ID] = entry
+	}
+
+	for id, entry := range seen {
+		if currentIDs[id] {
+			continue
+		}
+		events = append(events, registry.Event{Type: registry.EventRemoved, Service: entry})
+		delete(seen, id)
+	}
+
+	return events
+}
+
+// Shutdown cancels every registered service's Start(ctx) goroutine. It does
+// not deregister them from the Consul catalog — that's the agent's job on
+// process exit, or the caller's via Deregister. After Shutdown, the
+// Registry should not be reused.
+func (r *Registry) Shutdown() {
+	r.mu.Lock()
+	cancels := r.cancels
+	r.cancels = make(map[string]context.CancelFunc)
+	r.mu.Unlock()
+
+	for _, cancel := range cancels {
+		if cancel != nil {
+			cancel()
+		}
+	}
+	r.cancel()
+}
+
+// toServiceEntry converts a Consul AgentService into a registry.ServiceEntry.
+func toServiceEntry(svc *capi.AgentService) *registry.ServiceEntry {
+	txt := make(map[string]string, len(svc.Tags))
+	for _, tag := range svc.Tags {
+		key, val, ok := cutTag(tag)
+		if !ok {
+			continue
+		}
+		txt[key] = val
+	}
+
+	return &registry.ServiceEntry{
+		ID:         svc.ID,
+		Name:       svc.Service,
+		HostName:   svc.Address,
+		Port:       svc.Port,
+		Text:       svc.Tags,
+		TxtRecords: txt,
+	}
+}
+
+// cutTag splits a Consul tag of the form "key=value".
+func cutTag(tag string) (key, val string, ok bool) {
+	for i := 0; i < len(tag); i++ {
+		if tag[i] == '=' {
+			return tag[:i], tag[i+1:], true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,139 @@
+package consul
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	capi "github.com/hashicorp/consul/api"
+	"github.com/samtvlabs/mocktimism/service-discovery/registry"
+)
+
+// fakeService is a minimal registry.Service for tests that never touches
+// the network.
+type fakeService struct {
+	id string
+}
+
+func (f *fakeService) Hostname() string    { return f.id }
+func (f *fakeService) Port() int           { return 0 }
+func (f *fakeService) ServiceType() string { return "_test._tcp" }
+func (f *fakeService) ID() string          { return f.id }
+func (f *fakeService) Config() interface{} { return map[string]string(nil) }
+func (f *fakeService) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// newTestRegistry builds a Registry without dialing a Consul agent: New
+// does that via capi.NewClient, which the duplicate-ID reservation and
+// Shutdown logic exercised below don't actually need.
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &Registry{
+		cancels: make(map[string]context.CancelFunc),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+func TestRegister_DuplicateID(t *testing.T) {
+	r := newTestRegistry(t)
+	r.cancels["svc-1"] = nil
+
+	err := r.Register(&fakeService{id: "svc-1"})
+	if !errors.Is(err, registry.ErrDuplicateService) {
+		t.Fatalf("Register() error = %v, want ErrDuplicateService", err)
+	}
+}
+
+func TestShutdown_CancelsEveryRegisteredService(t *testing.T) {
+	r := newTestRegistry(t)
+
+	var cancelled []string
+	for _, id := range []string{"svc-1", "svc-2"} {
+		id := id
+		r.cancels[id] = func() { cancelled = append(cancelled, id) }
+	}
+
+	r.Shutdown()
+
+	if len(r.cancels) != 0 {
+		t.Fatalf("Shutdown left %d entries in r.cancels", len(r.cancels))
+	}
+	if len(cancelled) != 2 {
+		t.Fatalf("Shutdown cancelled %d services, want 2", len(cancelled))
+	}
+}
+
+func TestDiffCatalog_EmitsAddedUpdatedAndRemoved(t *testing.T) {
+	seen := map[string]*registry.ServiceEntry{
+		"stale": {ID: "stale"},
+		"old":   {ID: "old", Name: "v1"},
+	}
+
+	current := []*registry.ServiceEntry{
+		{ID: "old", Name: "v2"},
+		{ID: "new", Name: "v1"},
+	}
+
+	events := diffCatalog(seen, current)
+
+	var added, updated, removed int
+	for _, evt := range events {
+		switch evt.Type {
+		case registry.EventAdded:
+			added++
+			if evt.Service.ID != "new" {
+				t.Fatalf("EventAdded for %q, want \"new\"", evt.Service.ID)
+			}
+		case registry.EventUpdated:
+			updated++
+			if evt.Service.ID != "old" || evt.Service.Name != "v2" {
+				t.Fatalf("EventUpdated = %+v, want the refreshed \"old\" entry", evt.Service)
+			}
+		case registry.EventRemoved:
+			removed++
+			if evt.Service.ID != "stale" {
+				t.Fatalf("EventRemoved for %q, want \"stale\"", evt.Service.ID)
+			}
+		}
+	}
+	if added != 1 || updated != 1 || removed != 1 {
+		t.Fatalf("got %d added, %d updated, %d removed; want 1 each", added, updated, removed)
+	}
+
+	if _, ok := seen["stale"]; ok {
+		t.Fatal("diffCatalog left the removed entry in seen")
+	}
+	if seen["old"].Name != "v2" {
+		t.Fatalf("diffCatalog left seen[\"old\"] stale: %+v", seen["old"])
+	}
+	if _, ok := seen["new"]; !ok {
+		t.Fatal("diffCatalog did not add the new entry to seen")
+	}
+}
+
+func TestToServiceEntry(t *testing.T) {
+	svc := &capi.AgentService{
+		ID:      "svc-1",
+		Service: "my-service",
+		Address: "10.0.0.1",
+		Port:    8080,
+		Tags:    []string{"version=1.2.3", "not-a-tag"},
+	}
+
+	entry := toServiceEntry(svc)
+
+	if entry.ID != "svc-1" || entry.Name != "my-service" || entry.HostName != "10.0.0.1" || entry.Port != 8080 {
+		t.Fatalf("toServiceEntry() = %+v", entry)
+	}
+	if entry.TxtRecords["version"] != "1.2.3" {
+		t.Fatalf("toServiceEntry() TxtRecords = %v, want version=1.2.3", entry.TxtRecords)
+	}
+	if _, ok := entry.TxtRecords["not-a-tag"]; ok {
+		t.Fatal("toServiceEntry() parsed a tag with no '=' into TxtRecords")
+	}
+}
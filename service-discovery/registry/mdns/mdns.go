@@ -0,0 +1,565 @@
+// Package mdns implements registry.Registry on top of Zeroconf/mDNS-SD,
+// broadcasting and discovering services on the local network segment.
+package mdns
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grandcat/zeroconf"
+	"github.com/samtvlabs/mocktimism/service-discovery/registry"
+)
+
+const (
+	// defaultHealthCheckInterval is how often a locally registered service's
+	// optional HealthCheck is polled.
+	defaultHealthCheckInterval = 30 * time.Second
+	// defaultTTL is how long a remotely-discovered entry is kept around
+	// without being refreshed by another mDNS announcement before it's
+	// expired.
+	defaultTTL = 90 * time.Second
+	// defaultLookupTimeout bounds the fallback Browse that GetService issues
+	// when name isn't already known.
+	defaultLookupTimeout = 5 * time.Second
+)
+
+// registeredService bundles everything Registry needs to keep a locally
+// registered service alive and tear it down again on Deregister/Shutdown.
+type registeredService struct {
+	server       *zeroconf.Server
+	entry        *registry.ServiceEntry
+	cancel       context.CancelFunc
+	service      registry.Service
+	healthCancel context.CancelFunc
+}
+
+// discoveredEntry is a peer service learned about via Browse/Lookup. It is
+// kept in a keyspace separate from registeredService (which is keyed by
+// s.ID()) because a discovered entry is keyed by mDNS instance name, and
+// nothing guarantees those two namespaces are disjoint: a service's own
+// announcement (instance name == its Hostname()) is routinely delivered
+// back to it by multicast, and if ID() and Hostname() happen to coincide a
+// shared map would let the discovered copy silently clobber the live
+// registeredService, orphaning its *zeroconf.Server and Start(ctx)
+// goroutine.
+type discoveredEntry struct {
+	entry    *registry.ServiceEntry
+	lastSeen time.Time
+}
+
+// mdnsResolver is the subset of *zeroconf.Resolver's API Registry needs. It
+// exists so tests can substitute a fake and exercise Browse/Lookup-backed
+// code paths without doing real mDNS I/O.
+type mdnsResolver interface {
+	Browse(ctx context.Context, service, domain string, entries chan<- *zeroconf.ServiceEntry) error
+	Lookup(ctx context.Context, instance, service, domain string, entries chan<- *zeroconf.ServiceEntry) error
+}
+
+// Registry discovers and announces services using Zeroconf/mDNS-SD.
+type Registry struct {
+	resolver    mdnsResolver
+	services    map[string]*registeredService
+	discovered  map[string]*discoveredEntry
+	serviceType string
+
+	healthCheckInterval time.Duration
+	ttl                 time.Duration
+	lookupTimeout       time.Duration
+	events              chan registry.Event
+	logger              registry.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	mu     sync.Mutex
+}
+
+// stdLogger adapts the standard library's log package to registry.Logger.
+type stdLogger struct{}
+
+func (stdLogger) Printf(format string, args ...interface{}) { log.Printf(format, args...) }
+
+// Option configures optional behavior of a Registry constructed via New.
+type Option func(*Registry)
+
+// WithHealthCheckInterval overrides how often a locally registered service's
+// optional HealthCheck is polled. The default is 30s. d must be positive;
+// New returns an error otherwise, since a zero or negative interval can't
+// back a ticker.
+func WithHealthCheckInterval(d time.Duration) Option {
+	return func(r *Registry) { r.healthCheckInterval = d }
+}
+
+// WithTTL overrides how long a remotely-discovered entry survives without a
+// refreshing mDNS announcement before it is expired. The default is 90s. d
+// must be positive; New returns an error otherwise, since a zero or
+// negative TTL can't back a ticker.
+func WithTTL(d time.Duration) Option {
+	return func(r *Registry) { r.ttl = d }
+}
+
+// WithLogger overrides the Registry's logger, which otherwise writes to the
+// standard library's log package.
+func WithLogger(l registry.Logger) Option {
+	return func(r *Registry) { r.logger = l }
+}
+
+// WithLookupTimeout overrides how long GetService's fallback Browse waits
+// for an answer before giving up. The default is 5s.
+func WithLookupTimeout(d time.Duration) Option {
+	return func(r *Registry) { r.lookupTimeout = d }
+}
+
+// New initializes an mDNS-backed Registry for the given service type, e.g.
+// "_mocktimism._tcp".
+func New(serviceType string, opts ...Option) (*Registry, error) {
+	resolver, err := zeroconf.NewResolver(nil)
+	if err != nil {
+		return nil, fmt.Errorf("mdns: failed to initialize resolver: %w: %v", registry.ErrResolverUnavailable, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	r := &Registry{
+		resolver:            resolver,
+		services:            make(map[string]*registeredService),
+		discovered:          make(map[string]*discoveredEntry),
+		serviceType:         serviceType,
+		healthCheckInterval: defaultHealthCheckInterval,
+		ttl:                 defaultTTL,
+		lookupTimeout:       defaultLookupTimeout,
+		events:              make(chan registry.Event, 32),
+		logger:              stdLogger{},
+		ctx:                 ctx,
+		cancel:              cancel,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.ttl <= 0 {
+		cancel()
+		return nil, fmt.Errorf("mdns: ttl must be positive, got %s", r.ttl)
+	}
+	if r.healthCheckInterval <= 0 {
+		cancel()
+		return nil, fmt.Errorf("mdns: health check interval must be positive, got %s", r.healthCheckInterval)
+	}
+
+	go r.expireLoop()
+
+	return r, nil
+}
+
+// parseTxtRecords turns "key=value" TXT records into a map.
+func parseTxtRecords(records []string) map[string]string {
+	txt := make(map[string]string, len(records))
+	for _, r := range records {
+		key, val, ok := strings.Cut(r, "=")
+		if !ok {
+			continue
+		}
+		txt[key] = val
+	}
+	return txt
+}
+
+// Register announces s via mDNS and starts it under a goroutine tied to the
+// Registry's lifetime. Returns an error if a service is already registered
+// under the same ID.
+func (r *Registry) Register(s registry.Service) error {
+	var txtRecords []string
+	if configMap, ok := s.Config().(map[string]string); ok {
+		txtRecords = make([]string, 0, len(configMap))
+		for key, val := range configMap {
+			txtRecords = append(txtRecords, key+"="+val)
+		}
+	}
+
+	// Reserve the ID under the lock before doing the (slow, network-bound)
+	// zeroconf.Register call, so two concurrent Register calls for the same
+	// ID can't both pass the duplicate check and race to clobber each
+	// other's entry.
+	r.mu.Lock()
+	if _, exists := r.services[s.ID()]; exists {
+		r.mu.Unlock()
+		return fmt.Errorf("mdns: %w: %q", registry.ErrDuplicateService, s.ID())
+	}
+	r.services[s.ID()] = &registeredService{entry: &registry.ServiceEntry{ID: s.ID()}}
+	r.mu.Unlock()
+
+	server, err := zeroconf.Register(s.Hostname(), s.ServiceType(), "local.", s.Port(), txtRecords, nil)
+	if err != nil {
+		r.mu.Lock()
+		delete(r.services, s.ID())
+		r.mu.Unlock()
+		return fmt.Errorf("mdns: %w: service %q: %v", registry.ErrRegistrationFailed, s.ID(), err)
+	}
+
+	svcCtx, cancel := context.WithCancel(r.ctx)
+
+	svc := &registeredService{
+		server: server,
+		entry: &registry.ServiceEntry{
+			ID:         s.ID(),
+			Name:       s.ServiceType(),
+			HostName:   s.Hostname(),
+			Port:       s.Port(),
+			Text:       txtRecords,
+			TxtRecords: parseTxtRecords(txtRecords),
+		},
+		cancel:  cancel,
+		service: s,
+	}
+
+	r.mu.Lock()
+	r.services[s.ID()] = svc
+	r.mu.Unlock()
+
+	go func() {
+		if err := s.Start(svcCtx); err != nil && svcCtx.Err() == nil {
+			r.logger.Printf("mdns: service %s stopped: %v", s.ID(), err)
+		}
+	}()
+
+	if hc, ok := s.(registry.HealthChecker); ok {
+		healthCtx, healthCancel := context.WithCancel(r.ctx)
+		svc.healthCancel = healthCancel
+		go r.runHealthCheck(healthCtx, s.ID(), hc)
+	}
+
+	return nil
+}
+
+// runHealthCheck polls hc.HealthCheck on r.healthCheckInterval until ctx is
+// cancelled, re-announcing the service with a fresh mDNS TTL whenever a
+// check fails.
+func (r *Registry) runHealthCheck(ctx context.Context, id string, hc registry.HealthChecker) {
+	ticker := time.NewTicker(r.healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := hc.HealthCheck(ctx); err != nil {
+				r.logger.Printf("mdns: health check failed for service %s: %v", id, err)
+				if err := r.reannounce(id); err != nil {
+					r.logger.Printf("mdns: failed to re-announce service %s: %v", id, err)
+				}
+			}
+		}
+	}
+}
+
+// reannounce shuts down and re-registers id's mDNS server with the same
+// hostname/port/TXT records, giving it a fresh TTL. It emits an
+// EventUpdated on success.
+func (r *Registry) reannounce(id string) error {
+	r.mu.Lock()
+	svc, ok := r.services[id]
+	r.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("mdns: %w: %q", registry.ErrServiceNotFound, id)
+	}
+
+	if svc.server != nil {
+		svc.server.Shutdown()
+	}
+
+	server, err := zeroconf.Register(svc.entry.HostName, svc.service.ServiceType(), "local.", svc.entry.Port, svc.entry.Text, nil)
+	if err != nil {
+		return fmt.Errorf("mdns: failed to re-announce service %q: %w", id, err)
+	}
+
+	r.mu.Lock()
+	svc.server = server
+	r.mu.Unlock()
+
+	r.emit(registry.Event{Type: registry.EventUpdated, Service: svc.entry})
+	return nil
+}
+
+// emit delivers evt on r.events. Once the buffer is full it blocks until a
+// reader takes an event or the Registry is shut down.
+func (r *Registry) emit(evt registry.Event) {
+	select {
+	case r.events <- evt:
+	case <-r.ctx.Done():
+	}
+}
+
+// Events returns the Registry's shared stream of Added/Updated/Removed
+// topology events, fed by Browse/Watch discoveries, TTL expiry, and health
+// check re-announcements.
+func (r *Registry) Events() <-chan registry.Event {
+	return r.events
+}
+
+// expireLoop periodically removes remotely-discovered entries that haven't
+// been refreshed by an mDNS announcement within r.ttl.
+func (r *Registry) expireLoop() {
+	interval := r.ttl / 2
+	if interval <= 0 {
+		interval = r.ttl
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, entry := range r.pruneExpired() {
+				r.emit(registry.Event{Type: registry.EventRemoved, Service: entry})
+			}
+		}
+	}
+}
+
+// pruneExpired removes discovered entries that haven't been refreshed
+// within r.ttl and returns the ones it removed. It never touches
+// r.services: locally registered entries aren't subject to discovery TTL.
+func (r *Registry) pruneExpired() []*registry.ServiceEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var expired []*registry.ServiceEntry
+	for id, disc := range r.discovered {
+		if time.Since(disc.lastSeen) > r.ttl {
+			expired = append(expired, disc.entry)
+			delete(r.discovered, id)
+		}
+	}
+	return expired
+}
+
+// Deregister tears down the mDNS server for the given service ID, cancels
+// its Start(ctx) goroutine, and removes it from the registry.
+func (r *Registry) Deregister(id string) error {
+	r.mu.Lock()
+	svc, ok := r.services[id]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("mdns: %w: %q", registry.ErrServiceNotFound, id)
+	}
+	delete(r.services, id)
+	r.mu.Unlock()
+
+	if svc.cancel != nil {
+		svc.cancel()
+	}
+	if svc.healthCancel != nil {
+		svc.healthCancel()
+	}
+	if svc.server != nil {
+		svc.server.Shutdown()
+	}
+	return nil
+}
+
+// GetService returns the known entries whose service name matches name,
+// actively browsing for r.serviceType (bounded by r.lookupTimeout) if none
+// are cached yet. name identifies a service type/name, not a single mDNS
+// instance, so the fallback browses rather than looking up one instance.
+func (r *Registry) GetService(name string) ([]*registry.ServiceEntry, error) {
+	r.mu.Lock()
+	var matches []*registry.ServiceEntry
+	for _, svc := range r.services {
+		if svc.entry.Name == name || svc.entry.ID == name {
+			matches = append(matches, svc.entry)
+		}
+	}
+	for _, disc := range r.discovered {
+		if disc.entry.Name == name || disc.entry.ID == name {
+			matches = append(matches, disc.entry)
+		}
+	}
+	r.mu.Unlock()
+
+	if len(matches) > 0 {
+		return matches, nil
+	}
+
+	ctx, cancel := context.WithTimeout(r.ctx, r.lookupTimeout)
+	defer cancel()
+
+	discovered, err := r.Browse(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for entry := range discovered {
+		if entry.Name == name || entry.ID == name {
+			matches = append(matches, entry)
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("mdns: %w: %q", registry.ErrServiceNotFound, name)
+	}
+	return matches, nil
+}
+
+// ListServices returns every service the Registry currently knows about,
+// whether registered locally or discovered via Browse/Lookup.
+func (r *Registry) ListServices() ([]*registry.ServiceEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]*registry.ServiceEntry, 0, len(r.services)+len(r.discovered))
+	for _, svc := range r.services {
+		entries = append(entries, svc.entry)
+	}
+	for _, disc := range r.discovered {
+		entries = append(entries, disc.entry)
+	}
+	return entries, nil
+}
+
+// Watch browses for r.serviceType, which in turn feeds Added/Updated events
+// into Events() as peers are discovered, and forwards them (along with any
+// TTL-expiry Removed events and health-check Updated events already flowing
+// through Events()) until ctx is cancelled.
+func (r *Registry) Watch(ctx context.Context) (<-chan registry.Event, error) {
+	discovered, err := r.Browse(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan registry.Event)
+	go func() {
+		defer close(events)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-discovered:
+				if !ok {
+					return
+				}
+			case evt, ok := <-r.events:
+				if !ok {
+					return
+				}
+				select {
+				case events <- evt:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// Browse actively discovers peer services of r.serviceType in the "local."
+// domain. Discovered entries are merged into r.discovered (keyed by
+// instance name, separately from locally registered services) and streamed
+// out on the returned channel, closed when ctx is cancelled.
+func (r *Registry) Browse(ctx context.Context) (<-chan *registry.ServiceEntry, error) {
+	results := make(chan *zeroconf.ServiceEntry)
+	if err := r.resolver.Browse(ctx, r.serviceType, "local.", results); err != nil {
+		return nil, fmt.Errorf("mdns: failed to browse %q: %w", r.serviceType, err)
+	}
+
+	out := make(chan *registry.ServiceEntry)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case entry, ok := <-results:
+				if !ok {
+					return
+				}
+				se := r.mergeDiscoveredEntry(entry)
+				select {
+				case out <- se:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Lookup actively resolves a single instance of r.serviceType in the
+// "local." domain and merges it into r.discovered. It blocks until an entry
+// is found or ctx is cancelled.
+func (r *Registry) Lookup(ctx context.Context, instance string) (*registry.ServiceEntry, error) {
+	results := make(chan *zeroconf.ServiceEntry)
+	if err := r.resolver.Lookup(ctx, instance, r.serviceType, "local.", results); err != nil {
+		return nil, fmt.Errorf("mdns: failed to look up %q: %w", instance, err)
+	}
+
+	select {
+	case entry, ok := <-results:
+		if !ok {
+			return nil, fmt.Errorf("mdns: no entry found for %q", instance)
+		}
+		return r.mergeDiscoveredEntry(entry), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// mergeDiscoveredEntry records an entry returned by the resolver into
+// r.discovered, keyed by instance name (a keyspace distinct from the
+// s.ID()-keyed r.services, since mDNS routinely delivers a service its own
+// announcement back), refreshing its lastSeen so it survives TTL expiry,
+// and emits an Added or Updated event. It returns the merged ServiceEntry.
+func (r *Registry) mergeDiscoveredEntry(entry *zeroconf.ServiceEntry) *registry.ServiceEntry {
+	se := &registry.ServiceEntry{
+		ID:         entry.Instance,
+		Name:       entry.Service,
+		HostName:   entry.HostName,
+		Port:       entry.Port,
+		Text:       entry.Text,
+		TxtRecords: parseTxtRecords(entry.Text),
+	}
+
+	r.mu.Lock()
+	_, existed := r.discovered[se.ID]
+	r.discovered[se.ID] = &discoveredEntry{entry: se, lastSeen: time.Now()}
+	r.mu.Unlock()
+
+	evtType := registry.EventAdded
+	if existed {
+		evtType = registry.EventUpdated
+	}
+	r.emit(registry.Event{Type: evtType, Service: se})
+
+	return se
+}
+
+// Shutdown tears down every locally registered service's mDNS server and
+// cancels their Start(ctx) goroutines. After Shutdown, the Registry should
+// not be reused.
+func (r *Registry) Shutdown() {
+	r.mu.Lock()
+	services := r.services
+	r.services = make(map[string]*registeredService)
+	r.discovered = make(map[string]*discoveredEntry)
+	r.mu.Unlock()
+
+	for _, svc := range services {
+		if svc.cancel != nil {
+			svc.cancel()
+		}
+		if svc.healthCancel != nil {
+			svc.healthCancel()
+		}
+		if svc.server != nil {
+			svc.server.Shutdown()
+		}
+	}
+	r.cancel()
+}
@@ -0,0 +1,247 @@
+package mdns
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grandcat/zeroconf"
+	"github.com/samtvlabs/mocktimism/service-discovery/registry"
+)
+
+// fakeService is a minimal registry.Service for tests that never touches
+// the network: its Start just blocks on ctx like microService's does.
+type fakeService struct {
+	id string
+}
+
+func (f *fakeService) Hostname() string    { return f.id }
+func (f *fakeService) Port() int           { return 0 }
+func (f *fakeService) ServiceType() string { return "_test._tcp" }
+func (f *fakeService) ID() string          { return f.id }
+func (f *fakeService) Config() interface{} { return map[string]string(nil) }
+func (f *fakeService) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// fakeResolver is a test double for mdnsResolver: Browse/Lookup stream a
+// fixed set of entries to the given channel, then block until ctx is done,
+// mirroring how *zeroconf.Resolver keeps the channel open for the duration
+// of the browse/lookup.
+type fakeResolver struct {
+	entries []*zeroconf.ServiceEntry
+}
+
+func (f *fakeResolver) Browse(ctx context.Context, service, domain string, out chan<- *zeroconf.ServiceEntry) error {
+	go func() {
+		for _, e := range f.entries {
+			select {
+			case out <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+		<-ctx.Done()
+	}()
+	return nil
+}
+
+func (f *fakeResolver) Lookup(ctx context.Context, instance, service, domain string, out chan<- *zeroconf.ServiceEntry) error {
+	return f.Browse(ctx, service, domain, out)
+}
+
+// newTestRegistry builds a Registry without dialing the network: New does
+// that via zeroconf.NewResolver, which this package's lifecycle/TTL logic
+// doesn't actually need.
+func newTestRegistry(t *testing.T, ttl time.Duration) *Registry {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &Registry{
+		services:   make(map[string]*registeredService),
+		discovered: make(map[string]*discoveredEntry),
+		ttl:        ttl,
+		events:     make(chan registry.Event, 8),
+		logger:     stdLogger{},
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+func TestRegister_DuplicateID(t *testing.T) {
+	r := newTestRegistry(t, time.Minute)
+	r.services["svc-1"] = &registeredService{entry: &registry.ServiceEntry{ID: "svc-1"}}
+
+	err := r.Register(&fakeService{id: "svc-1"})
+	if !errors.Is(err, registry.ErrDuplicateService) {
+		t.Fatalf("Register() error = %v, want ErrDuplicateService", err)
+	}
+}
+
+// TestGetService_ToleratesInFlightRegistration is a regression test for the
+// bug where Register's duplicate-ID reservation left a registeredService
+// with a nil entry in r.services for the duration of the blocking
+// zeroconf.Register call; GetService/ListServices run concurrently during
+// that window used to panic on the nil *registry.ServiceEntry.
+func TestGetService_ToleratesInFlightRegistration(t *testing.T) {
+	r := newTestRegistry(t, time.Minute)
+	r.services["svc-1"] = &registeredService{entry: &registry.ServiceEntry{ID: "svc-1"}}
+
+	if _, err := r.GetService("svc-1"); err != nil {
+		t.Fatalf("GetService() error = %v", err)
+	}
+	if _, err := r.ListServices(); err != nil {
+		t.Fatalf("ListServices() error = %v", err)
+	}
+}
+
+// TestGetService_FallsBackToBrowse is a regression test for GetService's
+// uncached-name path: name identifies a service type/name (the Browse use
+// case), not a single mDNS instance, so the fallback must browse rather
+// than look up one instance by that name.
+func TestGetService_FallsBackToBrowse(t *testing.T) {
+	r := newTestRegistry(t, time.Minute)
+	r.serviceType = "_test._tcp"
+	r.lookupTimeout = 30 * time.Millisecond
+	r.resolver = &fakeResolver{entries: []*zeroconf.ServiceEntry{
+		{ServiceRecord: zeroconf.ServiceRecord{Instance: "peer-1", Service: "peer-service"}},
+	}}
+
+	matches, err := r.GetService("peer-service")
+	if err != nil {
+		t.Fatalf("GetService() error = %v", err)
+	}
+	if len(matches) != 1 || matches[0].ID != "peer-1" {
+		t.Fatalf("GetService() = %+v, want one entry for peer-1", matches)
+	}
+}
+
+func TestGetService_NotFoundAfterBrowseTimesOut(t *testing.T) {
+	r := newTestRegistry(t, time.Minute)
+	r.serviceType = "_test._tcp"
+	r.lookupTimeout = 20 * time.Millisecond
+	r.resolver = &fakeResolver{}
+
+	_, err := r.GetService("does-not-exist")
+	if !errors.Is(err, registry.ErrServiceNotFound) {
+		t.Fatalf("GetService() error = %v, want ErrServiceNotFound", err)
+	}
+}
+
+func TestDeregister_UnknownID(t *testing.T) {
+	r := newTestRegistry(t, time.Minute)
+
+	err := r.Deregister("does-not-exist")
+	if !errors.Is(err, registry.ErrServiceNotFound) {
+		t.Fatalf("Deregister() error = %v, want ErrServiceNotFound", err)
+	}
+}
+
+func TestDeregister_CancelsGoroutineAndRemovesEntry(t *testing.T) {
+	r := newTestRegistry(t, time.Minute)
+
+	svcCtx, cancel := context.WithCancel(r.ctx)
+	r.services["svc-1"] = &registeredService{
+		entry:  &registry.ServiceEntry{ID: "svc-1"},
+		cancel: cancel,
+	}
+
+	if err := r.Deregister("svc-1"); err != nil {
+		t.Fatalf("Deregister() error = %v", err)
+	}
+
+	select {
+	case <-svcCtx.Done():
+	default:
+		t.Fatal("Deregister did not cancel the service's context")
+	}
+	if _, ok := r.services["svc-1"]; ok {
+		t.Fatal("Deregister left the entry in r.services")
+	}
+}
+
+func TestShutdown_TearsDownEveryLocalService(t *testing.T) {
+	r := newTestRegistry(t, time.Minute)
+
+	var cancelled []string
+	for _, id := range []string{"svc-1", "svc-2"} {
+		id := id
+		_, cancel := context.WithCancel(r.ctx)
+		wrapped := func() { cancelled = append(cancelled, id); cancel() }
+		r.services[id] = &registeredService{
+			entry:  &registry.ServiceEntry{ID: id},
+			cancel: wrapped,
+		}
+	}
+
+	r.Shutdown()
+
+	if len(r.services) != 0 {
+		t.Fatalf("Shutdown left %d entries in r.services", len(r.services))
+	}
+	if len(cancelled) != 2 {
+		t.Fatalf("Shutdown cancelled %d services, want 2", len(cancelled))
+	}
+}
+
+// TestMergeDiscoveredEntry_DoesNotClobberLocalEntry is a regression test for
+// the bug where a discovered entry and a locally registered entry shared a
+// keyspace: mDNS delivers a service its own announcement, and when its
+// instance name (Hostname()) equals its ID(), the discovered copy used to
+// silently overwrite the registeredService, orphaning its server/cancel.
+func TestMergeDiscoveredEntry_DoesNotClobberLocalEntry(t *testing.T) {
+	r := newTestRegistry(t, time.Minute)
+
+	const id = "svc-1"
+	_, cancel := context.WithCancel(r.ctx)
+	t.Cleanup(cancel)
+	local := &registeredService{
+		entry:  &registry.ServiceEntry{ID: id},
+		cancel: cancel,
+	}
+	r.services[id] = local
+
+	r.mergeDiscoveredEntry(&zeroconf.ServiceEntry{
+		ServiceRecord: zeroconf.ServiceRecord{Instance: id},
+	})
+
+	if r.services[id] != local {
+		t.Fatal("mergeDiscoveredEntry overwrote the locally registered entry")
+	}
+	if _, ok := r.discovered[id]; !ok {
+		t.Fatal("mergeDiscoveredEntry did not record the discovered entry")
+	}
+}
+
+func TestPruneExpired(t *testing.T) {
+	r := newTestRegistry(t, 10*time.Millisecond)
+
+	r.discovered["stale"] = &discoveredEntry{
+		entry:    &registry.ServiceEntry{ID: "stale"},
+		lastSeen: time.Now().Add(-time.Hour),
+	}
+	r.discovered["fresh"] = &discoveredEntry{
+		entry:    &registry.ServiceEntry{ID: "fresh"},
+		lastSeen: time.Now(),
+	}
+	_, cancel := context.WithCancel(r.ctx)
+	t.Cleanup(cancel)
+	r.services["local"] = &registeredService{
+		entry:  &registry.ServiceEntry{ID: "local"},
+		cancel: cancel,
+	}
+
+	expired := r.pruneExpired()
+
+	if len(expired) != 1 || expired[0].ID != "stale" {
+		t.Fatalf("pruneExpired() = %v, want only \"stale\"", expired)
+	}
+	if _, ok := r.discovered["fresh"]; !ok {
+		t.Fatal("pruneExpired removed a non-expired discovered entry")
+	}
+	if _, ok := r.services["local"]; !ok {
+		t.Fatal("pruneExpired touched a locally registered entry")
+	}
+}
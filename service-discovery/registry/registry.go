@@ -0,0 +1,113 @@
+// Package registry defines the pluggable service-registry backend that
+// mocktimism's service discovery is built on. Concrete backends (mDNS,
+// static config, Consul/etcd, ...) live in sub-packages and all implement
+// the Registry interface defined here.
+package registry
+
+import (
+	"context"
+	"errors"
+)
+
+// Sentinel errors returned by Registry implementations. Implementations
+// should wrap one of these with fmt.Errorf's %w so callers can use
+// errors.Is regardless of backend.
+var (
+	// ErrDuplicateService is returned by Register when a service with the
+	// same ID is already registered.
+	ErrDuplicateService = errors.New("registry: service is already registered")
+	// ErrRegistrationFailed is returned by Register when the backend itself
+	// rejects or fails to complete the registration.
+	ErrRegistrationFailed = errors.New("registry: service registration failed")
+	// ErrResolverUnavailable is returned when a Registry cannot be
+	// constructed because its underlying resolver/client failed to
+	// initialize.
+	ErrResolverUnavailable = errors.New("registry: resolver unavailable")
+	// ErrServiceNotFound is returned by Deregister/GetService when no
+	// matching service is known.
+	ErrServiceNotFound = errors.New("registry: service not found")
+)
+
+// Logger is the minimal structured-logging capability a Registry needs.
+// *log.Logger satisfies it, as does any adapter around a richer logging
+// library.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// Service is what a caller hands to a Registry to announce itself.
+type Service interface {
+	// Returns the host name of the service.
+	Hostname() string
+	// Returns the port number on which the service is listening.
+	Port() int
+	// Returns the type of the service, e.g., "_myService._tcp".
+	ServiceType() string
+	// Returns a unique identifier for the service.
+	ID() string
+	// Returns a map containing service configuration key-value pairs.
+	Config() interface{}
+	// Starts the service.
+	Start(ctx context.Context) error
+}
+
+// HealthChecker is an optional capability a Service can implement to have
+// a Registry poll its health and re-announce it on failure. Services that
+// don't implement it are registered once and never health-checked.
+type HealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+// ServiceEntry describes a service known to a Registry, whether registered
+// locally or discovered remotely.
+type ServiceEntry struct {
+	ID         string
+	Name       string
+	HostName   string
+	Port       int
+	Text       []string
+	TxtRecords map[string]string
+}
+
+// EventType enumerates the kinds of topology changes a Registry can emit
+// from Watch.
+type EventType int
+
+const (
+	// EventAdded indicates a service was newly seen.
+	EventAdded EventType = iota
+	// EventUpdated indicates a previously seen service was seen again
+	// (re-announced or re-polled), whether or not its details changed.
+	EventUpdated
+	// EventRemoved indicates a previously seen service is gone.
+	EventRemoved
+)
+
+// Event is a single topology change reported by Watch.
+type Event struct {
+	Type    EventType
+	Service *ServiceEntry
+}
+
+// Registry is the pluggable backend interface that every service-discovery
+// implementation (mDNS, static, Consul/etcd, ...) must satisfy. This mirrors
+// the registry plugin pattern used by go-micro so that mocktimism can swap
+// backends without touching caller code.
+type Registry interface {
+	// Register announces s with the registry and spawns s.Start under a
+	// goroutine tied to the registry's own lifetime, so that cancelling via
+	// Deregister stops it; every backend (mDNS, static, Consul) honors this.
+	Register(s Service) error
+	// Deregister removes the service with the given ID from the registry.
+	Deregister(id string) error
+	// GetService returns all known entries for the given service name.
+	GetService(name string) ([]*ServiceEntry, error)
+	// Watch streams topology change events until ctx is cancelled.
+	Watch(ctx context.Context) (<-chan Event, error)
+	// ListServices returns every service the registry currently knows about.
+	ListServices() ([]*ServiceEntry, error)
+	// Shutdown cancels every registered service's Start(ctx) goroutine and
+	// tears down any backend-specific resources (e.g. mDNS servers). After
+	// Shutdown, the Registry should not be reused.
+	Shutdown()
+}
@@ -0,0 +1,184 @@
+// Package static implements registry.Registry by seeding a fixed set of
+// service entries from a JSON config file. It performs no network discovery,
+// which makes it useful in CI and containerized environments where
+// multicast (and therefore mDNS) is unavailable.
+package static
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/samtvlabs/mocktimism/service-discovery/registry"
+)
+
+// seedEntry is the on-disk shape of a single entry in the config file.
+type seedEntry struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	HostName   string            `json:"hostname"`
+	Port       int               `json:"port"`
+	TxtRecords map[string]string `json:"txt_records"`
+}
+
+// registeredEntry pairs a ServiceEntry with the means to stop the Start(ctx)
+// goroutine spawned for it. cancel is nil for entries seeded straight from
+// the config file, since there's no live Service behind them to start.
+type registeredEntry struct {
+	entry  *registry.ServiceEntry
+	cancel context.CancelFunc
+}
+
+// Registry serves a fixed set of services loaded once from a config file,
+// plus any registered at runtime via Register.
+type Registry struct {
+	mu       sync.Mutex
+	services map[string]*registeredEntry
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// New loads the config file at path and returns a Registry seeded with its
+// entries. The file must contain a JSON array of entries; see seedEntry.
+func New(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("static: failed to read config %q: %w", path, err)
+	}
+
+	var seeds []seedEntry
+	if err := json.Unmarshal(data, &seeds); err != nil {
+		return nil, fmt.Errorf("static: failed to parse config %q: %w", path, err)
+	}
+
+	services := make(map[string]*registeredEntry, len(seeds))
+	for _, seed := range seeds {
+		services[seed.ID] = &registeredEntry{entry: &registry.ServiceEntry{
+			ID:         seed.ID,
+			Name:       seed.Name,
+			HostName:   seed.HostName,
+			Port:       seed.Port,
+			TxtRecords: seed.TxtRecords,
+		}}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Registry{services: services, ctx: ctx, cancel: cancel}, nil
+}
+
+// Register adds s to the registry and spawns s.Start under a goroutine tied
+// to the Registry's lifetime, so Deregister can stop it. Returns
+// ErrDuplicateService if an entry with the same ID is already present.
+func (r *Registry) Register(s registry.Service) error {
+	var txtRecords map[string]string
+	if configMap, ok := s.Config().(map[string]string); ok {
+		txtRecords = configMap
+	}
+
+	// The check and insert happen under a single critical section (there's
+	// no blocking call in between, unlike mdns's network-bound
+	// zeroconf.Register) so two concurrent Register calls for the same ID
+	// can't both pass the duplicate check and race to clobber each other's
+	// entry.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.services[s.ID()]; exists {
+		return fmt.Errorf("static: %w: %q", registry.ErrDuplicateService, s.ID())
+	}
+
+	svcCtx, cancel := context.WithCancel(r.ctx)
+	r.services[s.ID()] = &registeredEntry{
+		entry: &registry.ServiceEntry{
+			ID:         s.ID(),
+			Name:       s.ServiceType(),
+			HostName:   s.Hostname(),
+			Port:       s.Port(),
+			TxtRecords: txtRecords,
+		},
+		cancel: cancel,
+	}
+
+	// Start errors aren't surfaced: unlike mdns.Registry, static has no
+	// Logger option to report them through.
+	go func() {
+		_ = s.Start(svcCtx)
+	}()
+
+	return nil
+}
+
+// Deregister removes the service with the given ID, cancelling its
+// Start(ctx) goroutine if one was spawned for it.
+func (r *Registry) Deregister(id string) error {
+	r.mu.Lock()
+	svc, ok := r.services[id]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("static: %w: %q", registry.ErrServiceNotFound, id)
+	}
+	delete(r.services, id)
+	r.mu.Unlock()
+
+	if svc.cancel != nil {
+		svc.cancel()
+	}
+	return nil
+}
+
+// GetService returns the entries whose name or ID matches name.
+func (r *Registry) GetService(name string) ([]*registry.ServiceEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matches []*registry.ServiceEntry
+	for _, svc := range r.services {
+		if svc.entry.Name == name || svc.entry.ID == name {
+			matches = append(matches, svc.entry)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("static: %w: %q", registry.ErrServiceNotFound, name)
+	}
+	return matches, nil
+}
+
+// ListServices returns every seeded and registered service.
+func (r *Registry) ListServices() ([]*registry.ServiceEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := make([]*registry.ServiceEntry, 0, len(r.services))
+	for _, svc := range r.services {
+		entries = append(entries, svc.entry)
+	}
+	return entries, nil
+}
+
+// Watch returns a channel that is immediately closed: no events are
+// streamed, not even for services added or removed via Register/Deregister.
+func (r *Registry) Watch(ctx context.Context) (<-chan registry.Event, error) {
+	events := make(chan registry.Event)
+	close(events)
+	return events, nil
+}
+
+// Shutdown cancels every registered service's Start(ctx) goroutine (seeded
+// entries have none to cancel) and removes every entry, seeded ones
+// included. After Shutdown, the Registry should not be reused.
+func (r *Registry) Shutdown() {
+	r.mu.Lock()
+	services := r.services
+	r.services = make(map[string]*registeredEntry)
+	r.mu.Unlock()
+
+	for _, svc := range services {
+		if svc.cancel != nil {
+			svc.cancel()
+		}
+	}
+	r.cancel()
+}
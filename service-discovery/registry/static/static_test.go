@@ -0,0 +1,179 @@
+package static
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/samtvlabs/mocktimism/service-discovery/registry"
+)
+
+// fakeService is a minimal registry.Service for tests that never touches
+// the network: its Start just blocks on ctx like microService's does.
+type fakeService struct {
+	id string
+}
+
+func (f *fakeService) Hostname() string    { return f.id }
+func (f *fakeService) Port() int           { return 0 }
+func (f *fakeService) ServiceType() string { return "_test._tcp" }
+func (f *fakeService) ID() string          { return f.id }
+func (f *fakeService) Config() interface{} { return map[string]string(nil) }
+func (f *fakeService) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// newTestRegistry builds a Registry without reading a config file: New
+// does that via os.ReadFile, which this package's Register/Deregister
+// lifecycle logic doesn't actually need.
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return &Registry{
+		services: make(map[string]*registeredEntry),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func TestNew_SeedsEntriesFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "seed.json")
+	seeds := []seedEntry{{ID: "svc-1", Name: "my-service", HostName: "10.0.0.1", Port: 8080}}
+	data, err := json.Marshal(seeds)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatalf("os.WriteFile() error = %v", err)
+	}
+
+	r, err := New(path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	matches, err := r.GetService("my-service")
+	if err != nil {
+		t.Fatalf("GetService() error = %v", err)
+	}
+	if len(matches) != 1 || matches[0].ID != "svc-1" {
+		t.Fatalf("GetService() = %+v, want one entry for svc-1", matches)
+	}
+}
+
+func TestRegister_DuplicateID(t *testing.T) {
+	r := newTestRegistry(t)
+	r.services["svc-1"] = &registeredEntry{entry: &registry.ServiceEntry{ID: "svc-1"}}
+
+	err := r.Register(&fakeService{id: "svc-1"})
+	if !errors.Is(err, registry.ErrDuplicateService) {
+		t.Fatalf("Register() error = %v, want ErrDuplicateService", err)
+	}
+}
+
+func TestDeregister_UnknownID(t *testing.T) {
+	r := newTestRegistry(t)
+
+	err := r.Deregister("does-not-exist")
+	if !errors.Is(err, registry.ErrServiceNotFound) {
+		t.Fatalf("Deregister() error = %v, want ErrServiceNotFound", err)
+	}
+}
+
+func TestDeregister_CancelsGoroutineAndRemovesEntry(t *testing.T) {
+	r := newTestRegistry(t)
+	svcCtx, cancel := context.WithCancel(r.ctx)
+	t.Cleanup(cancel)
+	r.services["svc-1"] = &registeredEntry{
+		entry:  &registry.ServiceEntry{ID: "svc-1"},
+		cancel: cancel,
+	}
+
+	if err := r.Deregister("svc-1"); err != nil {
+		t.Fatalf("Deregister() error = %v", err)
+	}
+
+	select {
+	case <-svcCtx.Done():
+	default:
+		t.Fatal("Deregister did not cancel the service's context")
+	}
+	if _, ok := r.services["svc-1"]; ok {
+		t.Fatal("Deregister left the entry in r.services")
+	}
+}
+
+func TestGetService_NotFound(t *testing.T) {
+	r := newTestRegistry(t)
+
+	_, err := r.GetService("does-not-exist")
+	if !errors.Is(err, registry.ErrServiceNotFound) {
+		t.Fatalf("GetService() error = %v, want ErrServiceNotFound", err)
+	}
+}
+
+func TestListServices_ReturnsSeededAndRegistered(t *testing.T) {
+	r := newTestRegistry(t)
+	r.services["seeded"] = &registeredEntry{entry: &registry.ServiceEntry{ID: "seeded"}}
+	if err := r.Register(&fakeService{id: "registered"}); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	entries, err := r.ListServices()
+	if err != nil {
+		t.Fatalf("ListServices() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("ListServices() = %d entries, want 2", len(entries))
+	}
+}
+
+func TestWatch_ReturnsAlreadyClosedChannel(t *testing.T) {
+	r := newTestRegistry(t)
+
+	events, err := r.Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Watch() error = %v", err)
+	}
+
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Fatal("Watch() channel delivered an event; want it closed with none")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Watch() channel was not already closed")
+	}
+}
+
+func TestShutdown_TearsDownEveryRegisteredService(t *testing.T) {
+	r := newTestRegistry(t)
+	r.services["seeded"] = &registeredEntry{entry: &registry.ServiceEntry{ID: "seeded"}}
+
+	var cancelled []string
+	for _, id := range []string{"svc-1", "svc-2"} {
+		id := id
+		_, cancel := context.WithCancel(r.ctx)
+		wrapped := func() { cancelled = append(cancelled, id); cancel() }
+		r.services[id] = &registeredEntry{
+			entry:  &registry.ServiceEntry{ID: id},
+			cancel: wrapped,
+		}
+	}
+
+	r.Shutdown()
+
+	if len(r.services) != 0 {
+		t.Fatalf("Shutdown left %d entries in r.services", len(r.services))
+	}
+	if len(cancelled) != 2 {
+		t.Fatalf("Shutdown cancelled %d services, want 2", len(cancelled))
+	}
+}
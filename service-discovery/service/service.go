@@ -0,0 +1,301 @@
+// Package service provides a request/response micro-service framework on
+// top of a registry.Registry, modeled on the NATS micro-services pattern:
+// callers add named endpoints to a Service, then Publish it so the registry
+// announces it (including its endpoint list via TXT/tag records), and every
+// Service exposes reserved $SRV.PING/$SRV.INFO/$SRV.STATS endpoints.
+//
+// Unlike NATS micro-services, there is no network transport here: Request
+// dispatches in-process against the *Service value the caller holds, not
+// over a subject a remote client can address. The reserved endpoints and
+// the published endpoint list are meant for discovery-side tooling that
+// walks registry.ServiceEntry.TxtRecords to enumerate what a peer exposes,
+// not for a remote process to actually call $SRV.PING/INFO/STATS.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samtvlabs/mocktimism/service-discovery/registry"
+)
+
+// Reserved endpoint subjects every Service exposes automatically.
+const (
+	PingSubject  = "$SRV.PING"
+	InfoSubject  = "$SRV.INFO"
+	StatsSubject = "$SRV.STATS"
+)
+
+// Request is delivered to an endpoint Handler.
+type Request struct {
+	Subject string
+	Data    []byte
+}
+
+// Response is returned by an endpoint Handler.
+type Response struct {
+	Data []byte
+}
+
+// Handler processes a Request and returns a Response or an error.
+type Handler func(ctx context.Context, req *Request) (*Response, error)
+
+// Config describes a Service to be created by AddService.
+type Config struct {
+	Name    string
+	Version string
+	// ID uniquely identifies this instance; generated from Name and the
+	// current time if empty.
+	ID string
+	// Hostname/Port/ServiceType are passed through to the underlying
+	// registry.Service registration.
+	Hostname    string
+	Port        int
+	ServiceType string
+	// Metadata is published alongside name/version/id/endpoints in the
+	// registry's TXT/tag records.
+	Metadata map[string]string
+}
+
+// EndpointStats tracks request metrics for a single endpoint.
+type EndpointStats struct {
+	NumRequests       int64
+	NumErrors         int64
+	ProcessingTimeSum time.Duration
+}
+
+// AverageProcessingTime returns the mean processing time across every
+// request handled so far, or 0 if none have.
+func (s EndpointStats) AverageProcessingTime() time.Duration {
+	if s.NumRequests == 0 {
+		return 0
+	}
+	return s.ProcessingTimeSum / time.Duration(s.NumRequests)
+}
+
+// Stats is a snapshot of a Service's request metrics, keyed by endpoint
+// subject.
+type Stats struct {
+	Endpoints map[string]EndpointStats
+}
+
+// Service is a request/response micro-service. AddService returns one
+// unpublished; call AddEndpoint for every handler the caller wants
+// announced, then Publish to register it with the registry.
+type Service interface {
+	ID() string
+	Name() string
+	Version() string
+
+	// AddEndpoint registers handler under subject. Only endpoints added
+	// before Publish is called are included in the published endpoint list;
+	// ones added afterward are reachable via Request but are not
+	// re-announced.
+	AddEndpoint(subject string, handler Handler) error
+	// Publish registers the service with its registry, announcing its
+	// current endpoint list. It must be called exactly once, after the
+	// caller is done adding endpoints it wants published.
+	Publish() error
+	// Request dispatches data to the handler registered for subject and
+	// returns its response, recording stats for subject.
+	Request(ctx context.Context, subject string, data []byte) (*Response, error)
+
+	Stats() Stats
+	Reset()
+	Stop() error
+}
+
+// microService is the default Service implementation. It also satisfies
+// registry.Service so it can be handed directly to a Registry's Register.
+type microService struct {
+	cfg Config
+	id  string
+
+	mu        sync.Mutex
+	endpoints map[string]Handler
+	stats     map[string]*EndpointStats
+
+	reg registry.Registry
+}
+
+// AddService creates a Service from cfg and wires up its reserved
+// $SRV.PING/$SRV.INFO/$SRV.STATS endpoints, but does not register it with
+// reg yet. Call AddEndpoint for any handlers the caller wants published,
+// then Publish to announce it.
+func AddService(reg registry.Registry, cfg Config) (Service, error) {
+	id := cfg.ID
+	if id == "" {
+		id = fmt.Sprintf("%s-%d", cfg.Name, time.Now().UnixNano())
+	}
+
+	ms := &microService{
+		cfg:       cfg,
+		id:        id,
+		endpoints: make(map[string]Handler),
+		stats:     make(map[string]*EndpointStats),
+		reg:       reg,
+	}
+	ms.addReservedEndpoints()
+
+	return ms, nil
+}
+
+// Publish registers ms with its registry, announcing the endpoints added so
+// far via ms.Config's TXT/tag records.
+func (ms *microService) Publish() error {
+	if err := ms.reg.Register(ms); err != nil {
+		return fmt.Errorf("service: failed to register %q: %w", ms.id, err)
+	}
+	return nil
+}
+
+// addReservedEndpoints wires up $SRV.PING/$SRV.INFO/$SRV.STATS.
+func (ms *microService) addReservedEndpoints() {
+	ms.endpoints[PingSubject] = func(ctx context.Context, req *Request) (*Response, error) {
+		return jsonResponse(map[string]string{
+			"name":    ms.cfg.Name,
+			"id":      ms.id,
+			"version": ms.cfg.Version,
+		})
+	}
+	ms.endpoints[InfoSubject] = func(ctx context.Context, req *Request) (*Response, error) {
+		return jsonResponse(map[string]interface{}{
+			"name":      ms.cfg.Name,
+			"id":        ms.id,
+			"version":   ms.cfg.Version,
+			"endpoints": ms.endpointNames(),
+		})
+	}
+	ms.endpoints[StatsSubject] = func(ctx context.Context, req *Request) (*Response, error) {
+		return jsonResponse(ms.Stats())
+	}
+	for subject := range ms.endpoints {
+		ms.stats[subject] = &EndpointStats{}
+	}
+}
+
+func jsonResponse(v interface{}) (*Response, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("service: failed to marshal response: %w", err)
+	}
+	return &Response{Data: data}, nil
+}
+
+// endpointNames returns the registered endpoint subjects, reserved ones
+// included, for publishing and for $SRV.INFO.
+func (ms *microService) endpointNames() []string {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	names := make([]string, 0, len(ms.endpoints))
+	for subject := range ms.endpoints {
+		names = append(names, subject)
+	}
+	return names
+}
+
+func (ms *microService) ID() string      { return ms.id }
+func (ms *microService) Name() string    { return ms.cfg.Name }
+func (ms *microService) Version() string { return ms.cfg.Version }
+
+// AddEndpoint registers handler under subject. See the Service interface
+// doc for the publish-timing caveat.
+func (ms *microService) AddEndpoint(subject string, handler Handler) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if _, exists := ms.endpoints[subject]; exists {
+		return fmt.Errorf("service: endpoint %q is already registered", subject)
+	}
+	ms.endpoints[subject] = handler
+	ms.stats[subject] = &EndpointStats{}
+	return nil
+}
+
+// Request dispatches data to the handler registered for subject, recording
+// its processing time and error count.
+func (ms *microService) Request(ctx context.Context, subject string, data []byte) (*Response, error) {
+	ms.mu.Lock()
+	handler, ok := ms.endpoints[subject]
+	stats := ms.stats[subject]
+	ms.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("service: no endpoint registered for %q", subject)
+	}
+
+	start := time.Now()
+	resp, err := handler(ctx, &Request{Subject: subject, Data: data})
+	elapsed := time.Since(start)
+
+	ms.mu.Lock()
+	stats.NumRequests++
+	stats.ProcessingTimeSum += elapsed
+	if err != nil {
+		stats.NumErrors++
+	}
+	ms.mu.Unlock()
+
+	return resp, err
+}
+
+// Stats returns a snapshot of request metrics for every endpoint.
+func (ms *microService) Stats() Stats {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	endpoints := make(map[string]EndpointStats, len(ms.stats))
+	for subject, s := range ms.stats {
+		endpoints[subject] = *s
+	}
+	return Stats{Endpoints: endpoints}
+}
+
+// Reset zeroes every endpoint's request metrics.
+func (ms *microService) Reset() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	for subject := range ms.stats {
+		ms.stats[subject] = &EndpointStats{}
+	}
+}
+
+// Stop deregisters the service from the registry, which cancels the
+// Start(ctx) goroutine the registry spawned for it.
+func (ms *microService) Stop() error {
+	return ms.reg.Deregister(ms.id)
+}
+
+// Hostname, Port, ServiceType, Config, and Start satisfy registry.Service so
+// microService can be handed directly to Registry.Register.
+
+func (ms *microService) Hostname() string    { return ms.cfg.Hostname }
+func (ms *microService) Port() int           { return ms.cfg.Port }
+func (ms *microService) ServiceType() string { return ms.cfg.ServiceType }
+
+// Config publishes name, version, id, and the current endpoint list
+// alongside any caller-supplied metadata as TXT/tag records.
+func (ms *microService) Config() interface{} {
+	cfg := make(map[string]string, len(ms.cfg.Metadata)+4)
+	for k, v := range ms.cfg.Metadata {
+		cfg[k] = v
+	}
+	cfg["name"] = ms.cfg.Name
+	cfg["version"] = ms.cfg.Version
+	cfg["id"] = ms.id
+	cfg["endpoints"] = strings.Join(ms.endpointNames(), ",")
+	return cfg
+}
+
+// Start blocks until ctx is cancelled; microService has no listen loop of
+// its own since requests are dispatched in-process via Request.
+func (ms *microService) Start(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
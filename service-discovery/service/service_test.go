@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/samtvlabs/mocktimism/service-discovery/registry"
+)
+
+// fakeRegistry is a minimal registry.Registry that records the Service
+// handed to Register/Deregister instead of doing any real registration.
+type fakeRegistry struct {
+	// publishedConfig is a snapshot of s.Config() taken at Register time,
+	// the same way static.Registry/mdns.Registry/consul.Registry each read
+	// it once and store the result rather than calling it again later.
+	publishedConfig map[string]string
+	deregistered    string
+}
+
+func (f *fakeRegistry) Register(s registry.Service) error {
+	if cfg, ok := s.Config().(map[string]string); ok {
+		f.publishedConfig = cfg
+	}
+	return nil
+}
+
+func (f *fakeRegistry) Deregister(id string) error {
+	f.deregistered = id
+	return nil
+}
+
+func (f *fakeRegistry) GetService(name string) ([]*registry.ServiceEntry, error) {
+	return nil, registry.ErrServiceNotFound
+}
+
+func (f *fakeRegistry) Watch(ctx context.Context) (<-chan registry.Event, error) {
+	events := make(chan registry.Event)
+	close(events)
+	return events, nil
+}
+
+func (f *fakeRegistry) ListServices() ([]*registry.ServiceEntry, error) { return nil, nil }
+
+func (f *fakeRegistry) Shutdown() {}
+
+func newTestService(t *testing.T) (*microService, *fakeRegistry) {
+	t.Helper()
+	reg := &fakeRegistry{}
+	svc, err := AddService(reg, Config{Name: "my-service", Version: "1.0.0"})
+	if err != nil {
+		t.Fatalf("AddService() error = %v", err)
+	}
+	return svc.(*microService), reg
+}
+
+func TestAddEndpoint_DuplicateSubject(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	handler := func(ctx context.Context, req *Request) (*Response, error) { return &Response{}, nil }
+	if err := svc.AddEndpoint("orders.create", handler); err != nil {
+		t.Fatalf("AddEndpoint() error = %v", err)
+	}
+
+	if err := svc.AddEndpoint("orders.create", handler); err == nil {
+		t.Fatal("AddEndpoint() error = nil, want an error for the duplicate subject")
+	}
+}
+
+func TestPublish_EndpointAddedAfterwardIsNotInPublishedList(t *testing.T) {
+	svc, reg := newTestService(t)
+	handler := func(ctx context.Context, req *Request) (*Response, error) { return &Response{}, nil }
+
+	if err := svc.AddEndpoint("orders.create", handler); err != nil {
+		t.Fatalf("AddEndpoint() error = %v", err)
+	}
+	if err := svc.Publish(); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if err := svc.AddEndpoint("orders.cancel", handler); err != nil {
+		t.Fatalf("AddEndpoint() error = %v", err)
+	}
+
+	endpoints := reg.publishedConfig["endpoints"]
+	if !containsSubject(endpoints, "orders.create") {
+		t.Fatalf("published endpoints %q missing orders.create", endpoints)
+	}
+	if containsSubject(endpoints, "orders.cancel") {
+		t.Fatalf("published endpoints %q should not include orders.cancel, added after Publish", endpoints)
+	}
+
+	// The late endpoint is still reachable via Request even though it
+	// wasn't (re-)announced.
+	if _, err := svc.Request(context.Background(), "orders.cancel", nil); err != nil {
+		t.Fatalf("Request() error = %v, want the late endpoint to still be callable", err)
+	}
+}
+
+func containsSubject(csv, subject string) bool {
+	for _, s := range splitCSV(csv) {
+		if s == subject {
+			return true
+		}
+	}
+	return false
+}
+
+func splitCSV(s string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == ',' {
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
+
+func TestRequest_RecordsStatsIncludingErrors(t *testing.T) {
+	svc, _ := newTestService(t)
+	wantErr := errors.New("boom")
+	if err := svc.AddEndpoint("fails", func(ctx context.Context, req *Request) (*Response, error) {
+		return nil, wantErr
+	}); err != nil {
+		t.Fatalf("AddEndpoint() error = %v", err)
+	}
+
+	if _, err := svc.Request(context.Background(), "fails", nil); !errors.Is(err, wantErr) {
+		t.Fatalf("Request() error = %v, want %v", err, wantErr)
+	}
+
+	stats := svc.Stats().Endpoints["fails"]
+	if stats.NumRequests != 1 || stats.NumErrors != 1 {
+		t.Fatalf("Stats() = %+v, want 1 request and 1 error", stats)
+	}
+}
+
+func TestRequest_UnknownSubject(t *testing.T) {
+	svc, _ := newTestService(t)
+
+	if _, err := svc.Request(context.Background(), "does-not-exist", nil); err == nil {
+		t.Fatal("Request() error = nil, want an error for an unregistered subject")
+	}
+}
+
+func TestReset_ZeroesStats(t *testing.T) {
+	svc, _ := newTestService(t)
+	if err := svc.AddEndpoint("ok", func(ctx context.Context, req *Request) (*Response, error) {
+		return &Response{}, nil
+	}); err != nil {
+		t.Fatalf("AddEndpoint() error = %v", err)
+	}
+	if _, err := svc.Request(context.Background(), "ok", nil); err != nil {
+		t.Fatalf("Request() error = %v", err)
+	}
+
+	svc.Reset()
+
+	stats := svc.Stats().Endpoints["ok"]
+	if stats.NumRequests != 0 || stats.NumErrors != 0 || stats.ProcessingTimeSum != 0 {
+		t.Fatalf("Stats() after Reset() = %+v, want all zero", stats)
+	}
+}
+
+func TestStop_DeregistersFromRegistry(t *testing.T) {
+	svc, reg := newTestService(t)
+
+	if err := svc.Stop(); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if reg.deregistered != svc.ID() {
+		t.Fatalf("Stop() deregistered %q, want %q", reg.deregistered, svc.ID())
+	}
+}
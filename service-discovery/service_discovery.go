@@ -1,89 +1,88 @@
-// Package servicediscovery provides tools and utilities to enable service discovery using Zeroconf.
+// Package servicediscovery selects and constructs a pluggable
+// registry.Registry backend (mDNS, static, Consul, ...) for mocktimism's
+// simulated L2 components to register and discover each other with.
 package servicediscovery
 
 import (
-	"context"
-	"log"
+	"fmt"
 
-	"github.com/grandcat/zeroconf"
+	capi "github.com/hashicorp/consul/api"
+	"github.com/samtvlabs/mocktimism/service-discovery/registry"
+	"github.com/samtvlabs/mocktimism/service-discovery/registry/consul"
+	"github.com/samtvlabs/mocktimism/service-discovery/registry/mdns"
+	"github.com/samtvlabs/mocktimism/service-discovery/registry/static"
 )
 
-// ServiceDiscovery manages service registration and discovery using Zeroconf.
-type ServiceDiscovery struct {
-	resolver    *zeroconf.Resolver
-	services    map[string]*zeroconf.ServiceEntry
-	serviceType string
-}
+// Service re-exports registry.Service so existing callers of this package
+// don't need to import the registry package directly.
+type Service = registry.Service
 
-// Service represents the interface that a service should implement
-// to be registered and discovered using ServiceDiscovery.
-type Service interface {
-	// Returns the host name of the service.
-	Hostname() string
-	// Returns the port number on which the service is listening.
-	Port() int
-	// Returns the type of the service, e.g., "_myService._tcp".
-	ServiceType() string
-	// Returns a unique identifier for the service.
-	ID() string
-	// Returns a map containing service configuration key-value pairs.
-	Config() interface{}
-	// Starts the service.
-	Start(ctx context.Context) error
-}
+// ServiceEntry re-exports registry.ServiceEntry for the same reason.
+type ServiceEntry = registry.ServiceEntry
 
-// NewServiceDiscovery initializes and returns a new ServiceDiscovery instance.
-// The serviceType argument specifies the type of services that the instance will manage.
-func NewServiceDiscovery(serviceType string) *ServiceDiscovery {
-	resolver, err := zeroconf.NewResolver(nil)
-	if err != nil {
-		log.Fatalf("Failed to initialize resolver: %v", err)
-	}
+type backendKind int
 
-	return &ServiceDiscovery{
-		resolver:    resolver,
-		services:    make(map[string]*zeroconf.ServiceEntry),
-		serviceType: serviceType,
-	}
+const (
+	backendMDNS backendKind = iota
+	backendStatic
+	backendConsul
+)
+
+// config collects the options passed to New.
+type config struct {
+	backend          backendKind
+	mdnsServiceType  string
+	staticConfigPath string
+	consulConfig     *capi.Config
 }
 
-// Register registers a given service with the ServiceDiscovery.
-// The provided service should implement the Service interface.
-func (sd *ServiceDiscovery) Register(s Service) {
-	var txtRecords []string
-	if configMap, ok := s.Config().(map[string]string); ok {
-		txtRecords = make([]string, 0, len(configMap))
-		for key, val := range configMap {
-			txtRecords = append(txtRecords, key+"="+val)
-		}
-	}
+// Option configures which Registry backend New constructs.
+type Option func(*config)
 
-	server, err := zeroconf.Register(s.Hostname(), s.ServiceType(), "local.", s.Port(), txtRecords, nil)
-	if err != nil {
-		log.Fatalf("Failed to register service: %v", err)
+// WithMDNS selects the mDNS/Zeroconf backend for the given service type,
+// e.g. "_mocktimism._tcp". This is the default backend if no Option is
+// given.
+func WithMDNS(serviceType string) Option {
+	return func(c *config) {
+		c.backend = backendMDNS
+		c.mdnsServiceType = serviceType
 	}
+}
 
-	// Store service for future reference
-	sd.services[s.ID()] = &zeroconf.ServiceEntry{
-		HostName: s.Hostname(),
-		Port:     s.Port(),
-		Text:     txtRecords,
+// WithStatic selects the static, config-file-seeded backend, useful in
+// CI/containerized environments where multicast doesn't work.
+func WithStatic(configPath string) Option {
+	return func(c *config) {
+		c.backend = backendStatic
+		c.staticConfigPath = configPath
 	}
-
-	defer server.Shutdown()
 }
 
-// GetServices returns a list of service IDs that are currently registered with the ServiceDiscovery.
-func (sd *ServiceDiscovery) GetServices() []string {
-	ids := make([]string, 0, len(sd.services))
-	for id := range sd.services {
-		ids = append(ids, id)
+// WithConsul selects the Consul-backed backend. A nil consulCfg falls back
+// to the Consul client library's defaults.
+func WithConsul(consulCfg *capi.Config) Option {
+	return func(c *config) {
+		c.backend = backendConsul
+		c.consulConfig = consulCfg
 	}
-	return ids
 }
 
-// GetServiceById retrieves a registered service based on its ID.
-// Returns nil if the ID does not match any registered service.
-func (sd *ServiceDiscovery) GetServiceById(id string) *zeroconf.ServiceEntry {
-	return sd.services[id]
+// New constructs a registry.Registry using the backend selected by opts.
+// With no options it defaults to mDNS with serviceType "_mocktimism._tcp".
+func New(opts ...Option) (registry.Registry, error) {
+	c := &config{backend: backendMDNS, mdnsServiceType: "_mocktimism._tcp"}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	switch c.backend {
+	case backendMDNS:
+		return mdns.New(c.mdnsServiceType)
+	case backendStatic:
+		return static.New(c.staticConfigPath)
+	case backendConsul:
+		return consul.New(c.consulConfig)
+	default:
+		return nil, fmt.Errorf("servicediscovery: unknown backend %v", c.backend)
+	}
 }